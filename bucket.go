@@ -0,0 +1,301 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package faucet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// emptyTickMinWait is the minimum amount of time bucketWorker waits between ticks that don't produce a value (i.e.
+// every input returned ok=false), even when a token is immediately available, so that an idle input (e.g. AddReader
+// on a quiet io.Reader) can't spin the worker at full CPU with zero-wait ticks.
+const emptyTickMinWait = time.Millisecond
+
+// bucket implements a token bucket: tokens accrue at a rate of one per rate, up to a ceiling of burst, see
+// Pipe.StartBucket and Pipe.SetLimit.
+type bucket struct {
+	mutex sync.Mutex
+
+	rate  time.Duration
+	burst int
+
+	tokens float64
+	last   time.Time
+
+	// changed is signalled (non-blocking, best-effort) by setLimit, so bucketWorker can wake up and recompute its
+	// wait immediately, rather than sleeping out a timer based on the stale rate.
+	changed chan struct{}
+}
+
+// noopTicker is used as a placeholder for Pipe.ticker while the worker runs in token-bucket mode (see
+// Pipe.StartBucket), it never ticks, only Stop is ever called on it, by Pipe.cleanup.
+type noopTicker struct{}
+
+func (noopTicker) C() <-chan time.Time { return nil }
+
+func (noopTicker) Stop() {}
+
+func newBucket(rate time.Duration, burst int) *bucket {
+	return &bucket{
+		rate:    rate,
+		burst:   burst,
+		tokens:  float64(burst),
+		last:    time.Now(),
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// setLimit updates the rate and burst, clamping the current token count to the new burst ceiling, and wakes a
+// bucketWorker that's currently parked waiting on the old rate, see bucket.changed.
+func (b *bucket) setLimit(rate time.Duration, burst int) {
+	b.mutex.Lock()
+	b.rate = rate
+	b.burst = burst
+	if b.tokens > float64(burst) {
+		b.tokens = float64(burst)
+	}
+	b.mutex.Unlock()
+
+	select {
+	case b.changed <- struct{}{}:
+	default:
+	}
+}
+
+// nextTokenIn accrues tokens for elapsed time since the last call, then returns 0 if a token is immediately
+// available, or else the duration until one will be.
+func (b *bucket) nextTokenIn(now time.Time) time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.last = now
+		b.tokens += elapsed.Seconds() / b.rate.Seconds()
+		if b.tokens > float64(b.burst) {
+			b.tokens = float64(b.burst)
+		}
+	}
+
+	if b.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) * float64(b.rate))
+}
+
+// consume takes one token, note it will go negative if called without a prior nextTokenIn returning 0, which
+// simply delays the next token's availability, rather than panicking or blocking.
+func (b *bucket) consume() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.tokens--
+}
+
+// StartBucket initialises the pipe worker in token-bucket mode: tokens accrue at a rate of one per rate, up to a
+// ceiling of burst, and each tick that successfully transfers a value (see doTick) consumes one token; ticks that
+// don't produce a value from any input are free, and never consume a token. When the bucket is empty, the worker
+// blocks until the next token is available, rather than ticking at a fixed interval, which allows short bursts of
+// up to burst transfers back-to-back. See SetLimit to adjust the rate and burst at runtime.
+// Each pipe may be started exactly once, via either Start or StartBucket, and it will panic if ctx or the pipe are
+// nil, or rate or burst are not greater than zero.
+func (p *Pipe) StartBucket(ctx context.Context, rate time.Duration, burst int) {
+	p.ensure()
+
+	if ctx == nil {
+		panic(errors.New("faucet.Pipe.StartBucket nil context"))
+	}
+
+	if rate <= 0 {
+		panic(errors.New("faucet.Pipe.StartBucket rate <= 0"))
+	}
+
+	if burst <= 0 {
+		panic(errors.New("faucet.Pipe.StartBucket burst <= 0"))
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.ticker != nil {
+		panic(errors.New("faucet.Pipe.StartBucket already started"))
+	}
+
+	p.ticker = noopTicker{}
+	p.bucket = newBucket(rate, burst)
+	p.ctx, p.cancel = context.WithCancel(ctx)
+
+	if p.monitor != nil {
+		p.monitor.onStart(time.Now())
+	}
+
+	go p.bucketWorker()
+	go p.cleanup()
+}
+
+// SetLimit adjusts the token bucket's rate and burst at runtime, note it will panic if the pipe is nil, or rate or
+// burst are not greater than zero. It has no effect unless the pipe was started via StartBucket.
+func (p *Pipe) SetLimit(rate time.Duration, burst int) {
+	p.ensure()
+
+	if rate <= 0 {
+		panic(errors.New("faucet.Pipe.SetLimit rate <= 0"))
+	}
+
+	if burst <= 0 {
+		panic(errors.New("faucet.Pipe.SetLimit burst <= 0"))
+	}
+
+	p.mutex.Lock()
+	b := p.bucket
+	p.mutex.Unlock()
+
+	if b != nil {
+		b.setLimit(rate, burst)
+	}
+}
+
+func (p *Pipe) bucketWorker() {
+	defer close(p.done)
+	defer p.cancel()
+	defer func() {
+		if p.monitor != nil {
+			p.monitor.onStop()
+		}
+	}()
+
+	var (
+		err       error
+		lastInput int
+	)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("faucet.Pipe.input.%d recovered from panic (%T): %+v", lastInput, r, r)
+		}
+
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+
+		p.err = err
+	}()
+
+	for i := 0; ; i++ {
+		if p.stopped() {
+			return
+		}
+
+		err = p.ctx.Err()
+
+		if err != nil {
+			// Stop may have canceled p.ctx in the gap since the p.stopped() check above; recheck so that's
+			// reported as a clean stop, not a context error
+			if p.stopped() {
+				err = nil
+				return
+			}
+			err = fmt.Errorf("faucet.Pipe context error: %v", err)
+			return
+		}
+
+		wait := p.bucket.nextTokenIn(time.Now())
+
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+
+			select {
+			case <-p.stop:
+				timer.Stop()
+				return
+
+			case <-p.ctx.Done():
+				timer.Stop()
+				// context canceled: if that was because Stop was called (which also cancels p.ctx, to unstick
+				// any blocked input/output call), this is a clean stop, not an error
+				if p.stopped() {
+					return
+				}
+				err = fmt.Errorf("faucet.Pipe context error: %v", p.ctx.Err())
+				return
+
+			case <-p.bucket.changed:
+				// rate/burst changed while waiting on the old rate, recompute the wait from scratch
+				timer.Stop()
+				continue
+
+			case <-timer.C:
+			}
+		} else {
+			select {
+			case <-p.stop:
+				return
+
+			case <-p.ctx.Done():
+				if p.stopped() {
+					return
+				}
+				err = fmt.Errorf("faucet.Pipe context error: %v", p.ctx.Err())
+				return
+
+			default:
+			}
+		}
+
+		moved, ok := p.doTick(i, &lastInput, &err)
+
+		if !ok {
+			return
+		}
+
+		if moved {
+			p.bucket.consume()
+			continue
+		}
+
+		if wait > 0 {
+			// already waited for a token above, no need for an additional floor wait
+			continue
+		}
+
+		// a token was available, but no input produced a value this tick: enforce a minimum wait before polling
+		// again, so an idle input can't busy-spin the worker
+		timer := time.NewTimer(emptyTickMinWait)
+
+		select {
+		case <-p.stop:
+			timer.Stop()
+			return
+
+		case <-p.ctx.Done():
+			timer.Stop()
+			if p.stopped() {
+				return
+			}
+			err = fmt.Errorf("faucet.Pipe context error: %v", p.ctx.Err())
+			return
+
+		case <-p.bucket.changed:
+			timer.Stop()
+
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,235 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package faucet
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPipe_StartBucket_burst(t *testing.T) {
+	var (
+		pipe  Pipe
+		mutex sync.Mutex
+		count int
+	)
+
+	pipe.AddInput(
+		func(ctx context.Context) (interface{}, bool, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			count++
+			return count, true, nil
+		},
+	)
+
+	values := make(chan interface{}, 3)
+
+	pipe.AddOutput(
+		func(ctx context.Context, value interface{}) error {
+			values <- value
+			return nil
+		},
+	)
+
+	// a full bucket should allow burst consecutive transfers with no waiting
+	pipe.StartBucket(context.Background(), time.Hour, 3)
+
+	for i := 1; i <= 3; i++ {
+		select {
+		case v := <-values:
+			if v != i {
+				t.Fatal("unexpected value", v)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for burst value", i)
+		}
+	}
+
+	pipe.Stop()
+	<-pipe.Done()
+
+	if err := pipe.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipe_StartBucket_rateLimited(t *testing.T) {
+	var pipe Pipe
+
+	pipe.AddInput(
+		func(ctx context.Context) (interface{}, bool, error) {
+			return 1, true, nil
+		},
+	)
+
+	values := make(chan interface{})
+
+	pipe.AddOutput(
+		func(ctx context.Context, value interface{}) error {
+			values <- value
+			return nil
+		},
+	)
+
+	pipe.StartBucket(context.Background(), time.Millisecond*100, 1)
+
+	<-values
+
+	select {
+	case <-values:
+		t.Fatal("expected the second transfer to be rate limited")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	<-values
+
+	pipe.Stop()
+	<-pipe.Done()
+
+	if err := pipe.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipe_StartBucket_emptyInputDoesNotConsumeToken(t *testing.T) {
+	var (
+		pipe        Pipe
+		polled      = make(chan struct{}, 1)
+		produceLock sync.Mutex
+		produce     bool
+	)
+
+	pipe.AddInput(
+		func(ctx context.Context) (interface{}, bool, error) {
+			select {
+			case polled <- struct{}{}:
+			default:
+			}
+			produceLock.Lock()
+			defer produceLock.Unlock()
+			if !produce {
+				return nil, false, nil
+			}
+			return 1, true, nil
+		},
+	)
+
+	values := make(chan interface{})
+
+	pipe.AddOutput(
+		func(ctx context.Context, value interface{}) error {
+			values <- value
+			return nil
+		},
+	)
+
+	pipe.StartBucket(context.Background(), time.Hour, 1)
+
+	<-polled
+
+	produceLock.Lock()
+	produce = true
+	produceLock.Unlock()
+
+	select {
+	case <-values:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a transfer after an empty poll")
+	}
+
+	pipe.Stop()
+	<-pipe.Done()
+
+	if err := pipe.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipe_SetLimit(t *testing.T) {
+	var pipe Pipe
+
+	pipe.AddInput(
+		func(ctx context.Context) (interface{}, bool, error) {
+			return 1, true, nil
+		},
+	)
+
+	values := make(chan interface{})
+
+	pipe.AddOutput(
+		func(ctx context.Context, value interface{}) error {
+			values <- value
+			return nil
+		},
+	)
+
+	pipe.StartBucket(context.Background(), time.Hour, 1)
+
+	<-values
+
+	// the bucket is now empty, and would otherwise take an hour to refill
+	pipe.SetLimit(time.Millisecond*10, 1)
+
+	select {
+	case <-values:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for SetLimit to take effect")
+	}
+
+	pipe.Stop()
+	<-pipe.Done()
+
+	if err := pipe.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipe_StartBucket_nilCtx(t *testing.T) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	var pipe Pipe
+	pipe.StartBucket(nil, time.Second, 1)
+}
+
+func TestPipe_StartBucket_zeroRate(t *testing.T) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	var pipe Pipe
+	pipe.StartBucket(context.Background(), 0, 1)
+}
+
+func TestPipe_StartBucket_zeroBurst(t *testing.T) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	var pipe Pipe
+	pipe.StartBucket(context.Background(), time.Second, 0)
+}
@@ -14,7 +14,9 @@
    limitations under the License.
  */
 
-// Package faucet implements a simple pattern for polling based rate limiting, using Golang's time.Ticker.
+// Package faucet implements a simple pattern for polling based rate limiting, using either a fixed-rate Ticker
+// (see Pipe.Start) or a token bucket with burst capacity (see Pipe.StartBucket). Which input is polled first each
+// tick is governed by a pluggable SelectStrategy (see Pipe.SetSelectStrategy).
 // Note that any nil arguments to any method or function in this package will trigger a panic.
 package faucet
 
@@ -39,11 +41,30 @@ type (
 		done chan struct{}
 		stop chan struct{}
 
-		ticker *time.Ticker
-		inputs []func(context.Context) (interface{}, bool, error)
-		outputs []func(context.Context, interface{}) error
-		ctx     context.Context
-		cancel  context.CancelFunc
+		ticker         Ticker
+		newTicker      TickerFunc
+		defaultTimeout time.Duration
+		inputs         []pipeInput
+		outputs        []pipeOutput
+		monitor        *Monitor
+		bucket         *bucket
+		selectStrategy SelectStrategy
+		ctx            context.Context
+		cancel         context.CancelFunc
+	}
+
+	// pipeInput pairs a registered input with its optional per-call timeout and select weight, see AddInput,
+	// AddInputWithTimeout and AddInputWithWeight.
+	pipeInput struct {
+		fn      func(context.Context) (interface{}, bool, error)
+		timeout time.Duration
+		weight  int
+	}
+
+	// pipeOutput pairs a registered output with its optional per-call timeout, see AddOutput and AddOutputWithTimeout.
+	pipeOutput struct {
+		fn      func(context.Context, interface{}) error
+		timeout time.Duration
 	}
 )
 
@@ -0,0 +1,174 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package faucet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// deadlineReader is implemented by io.Reader implementations (e.g. net.Conn) that support a read deadline, see
+// AddDeadlineReader.
+type deadlineReader interface {
+	io.Reader
+	SetReadDeadline(t time.Time) error
+}
+
+// deadlineWriter is implemented by io.Writer implementations (e.g. net.Conn) that support a write deadline, see
+// AddDeadlineWriter.
+type deadlineWriter interface {
+	io.Writer
+	SetWriteDeadline(t time.Time) error
+}
+
+// AddReader registers an input on p that reads up to chunk bytes at a time from r, delivering each non-empty read
+// as a []byte value. A zero-byte read with no error is treated as no value for this tick (ok=false); io.EOF is
+// treated as a terminal condition, stopping p (via p.Stop) without an error, note it will panic if p or r are nil,
+// or chunk is not greater than zero.
+func AddReader(p *Pipe, r io.Reader, chunk int) {
+	if r == nil {
+		panic(errors.New("faucet.AddReader nil r"))
+	}
+
+	if chunk <= 0 {
+		panic(errors.New("faucet.AddReader chunk <= 0"))
+	}
+
+	p.AddInput(newReaderInput(p, r, chunk))
+}
+
+// AddDeadlineReader behaves like AddReader, except that if r implements a SetReadDeadline method (as net.Conn
+// does), it is called with a deadline of deadline from now, before every read, so a stalled peer cannot wedge the
+// pipe. Note it will panic if p or r are nil, or chunk or deadline are not greater than zero.
+func AddDeadlineReader(p *Pipe, r io.Reader, chunk int, deadline time.Duration) {
+	if r == nil {
+		panic(errors.New("faucet.AddDeadlineReader nil r"))
+	}
+
+	if chunk <= 0 {
+		panic(errors.New("faucet.AddDeadlineReader chunk <= 0"))
+	}
+
+	if deadline <= 0 {
+		panic(errors.New("faucet.AddDeadlineReader deadline <= 0"))
+	}
+
+	fn := newReaderInput(p, r, chunk)
+
+	if dr, ok := r.(deadlineReader); ok {
+		fn = newDeadlineInput(dr, deadline, fn)
+	}
+
+	p.AddInput(fn)
+}
+
+// AddWriter registers an output on p that writes each value (asserted as []byte) to w, note it will panic if p or
+// w are nil.
+func AddWriter(p *Pipe, w io.Writer) {
+	if w == nil {
+		panic(errors.New("faucet.AddWriter nil w"))
+	}
+
+	p.AddOutput(newWriterOutput(w))
+}
+
+// AddDeadlineWriter behaves like AddWriter, except that if w implements a SetWriteDeadline method (as net.Conn
+// does), it is called with a deadline of deadline from now, before every write, so a stalled peer cannot wedge the
+// pipe. Note it will panic if p or w are nil, or deadline is not greater than zero.
+func AddDeadlineWriter(p *Pipe, w io.Writer, deadline time.Duration) {
+	if w == nil {
+		panic(errors.New("faucet.AddDeadlineWriter nil w"))
+	}
+
+	if deadline <= 0 {
+		panic(errors.New("faucet.AddDeadlineWriter deadline <= 0"))
+	}
+
+	fn := newWriterOutput(w)
+
+	if dw, ok := w.(deadlineWriter); ok {
+		wrapped := fn
+		fn = func(ctx context.Context, value interface{}) error {
+			if err := dw.SetWriteDeadline(time.Now().Add(deadline)); err != nil {
+				return err
+			}
+			return wrapped(ctx, value)
+		}
+	}
+
+	p.AddOutput(fn)
+}
+
+func newReaderInput(p *Pipe, r io.Reader, chunk int) func(context.Context) (interface{}, bool, error) {
+	buf := make([]byte, chunk)
+
+	return func(context.Context) (interface{}, bool, error) {
+		n, err := r.Read(buf)
+
+		if n > 0 {
+			value := make([]byte, n)
+			copy(value, buf[:n])
+
+			if err != nil && err != io.EOF {
+				return value, true, err
+			}
+
+			return value, true, nil
+		}
+
+		if err == nil {
+			// zero-byte, no error read, no value for this tick
+			return nil, false, nil
+		}
+
+		if err == io.EOF {
+			p.Stop()
+			return nil, false, nil
+		}
+
+		return nil, false, err
+	}
+}
+
+func newWriterOutput(w io.Writer) func(context.Context, interface{}) error {
+	return func(ctx context.Context, value interface{}) error {
+		b, ok := value.([]byte)
+		if !ok {
+			return fmt.Errorf("faucet.AddWriter expected []byte, got %T", value)
+		}
+
+		_, err := w.Write(b)
+		return err
+	}
+}
+
+func newDeadlineInput(
+	dr deadlineReader,
+	deadline time.Duration,
+	fn func(context.Context) (interface{}, bool, error),
+) func(context.Context) (interface{}, bool, error) {
+	return func(ctx context.Context) (interface{}, bool, error) {
+		if err := dr.SetReadDeadline(time.Now().Add(deadline)); err != nil {
+			return nil, false, err
+		}
+
+		return fn(ctx)
+	}
+}
@@ -0,0 +1,261 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package faucet
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAddReader_chunksAndEOFStopsPipe(t *testing.T) {
+	var (
+		pipe Pipe
+		lt   = NewLogicalTicker()
+		r    = bytes.NewReader([]byte("abcdef"))
+	)
+
+	AddReader(&pipe, r, 4)
+
+	values := make(chan interface{})
+
+	pipe.AddOutput(
+		func(ctx context.Context, value interface{}) error {
+			values <- value
+			return nil
+		},
+	)
+
+	pipe.Start(context.Background(), time.Hour, WithTickerFunc(func(time.Duration) Ticker { return lt }))
+
+	if v := string((<-values).([]byte)); v != "abcd" {
+		t.Fatal("unexpected value", v)
+	}
+
+	lt.Advance(time.Hour)
+	if v := string((<-values).([]byte)); v != "ef" {
+		t.Fatal("unexpected value", v)
+	}
+
+	// the next poll will observe io.EOF, and stop the pipe without an error
+	lt.Advance(time.Hour)
+
+	<-pipe.Done()
+
+	if err := pipe.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAddWriter_success(t *testing.T) {
+	var (
+		pipe Pipe
+		buf  bytes.Buffer
+	)
+
+	pipe.AddInput(
+		func(ctx context.Context) (interface{}, bool, error) {
+			return []byte("x"), true, nil
+		},
+	)
+
+	AddWriter(&pipe, &buf)
+
+	written := make(chan struct{}, 1)
+
+	pipe.AddOutput(
+		func(ctx context.Context, value interface{}) error {
+			select {
+			case written <- struct{}{}:
+			default:
+			}
+			return nil
+		},
+	)
+
+	pipe.Start(context.Background(), time.Millisecond)
+
+	<-written
+
+	pipe.Stop()
+	<-pipe.Done()
+
+	if err := pipe.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.Len() == 0 {
+		t.Fatal("expected at least one write")
+	}
+}
+
+func TestAddWriter_typeError(t *testing.T) {
+	var (
+		pipe Pipe
+		buf  bytes.Buffer
+	)
+
+	pipe.AddInput(
+		func(ctx context.Context) (interface{}, bool, error) {
+			return "not bytes", true, nil
+		},
+	)
+
+	AddWriter(&pipe, &buf)
+
+	pipe.Start(context.Background(), time.Millisecond)
+
+	<-pipe.Done()
+
+	if err := pipe.Err(); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestAddReader_nilPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	var pipe Pipe
+	AddReader(&pipe, nil, 1)
+}
+
+func TestAddReader_zeroChunkPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	var pipe Pipe
+	AddReader(&pipe, bytes.NewReader(nil), 0)
+}
+
+func TestAddWriter_nilPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	var pipe Pipe
+	AddWriter(&pipe, nil)
+}
+
+// deadlineConn implements deadlineReader and deadlineWriter, recording each deadline it's given.
+type deadlineConn struct {
+	mutex          sync.Mutex
+	r              *bytes.Reader
+	w              *bytes.Buffer
+	readDeadlines  []time.Time
+	writeDeadlines []time.Time
+}
+
+func (c *deadlineConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+func (c *deadlineConn) Write(p []byte) (int, error) {
+	return c.w.Write(p)
+}
+
+func (c *deadlineConn) SetReadDeadline(t time.Time) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.readDeadlines = append(c.readDeadlines, t)
+	return nil
+}
+
+func (c *deadlineConn) SetWriteDeadline(t time.Time) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.writeDeadlines = append(c.writeDeadlines, t)
+	return nil
+}
+
+func (c *deadlineConn) numReadDeadlines() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.readDeadlines)
+}
+
+func (c *deadlineConn) numWriteDeadlines() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.writeDeadlines)
+}
+
+func TestAddDeadlineReader_setsDeadline(t *testing.T) {
+	var (
+		pipe Pipe
+		conn = &deadlineConn{r: bytes.NewReader([]byte("hello"))}
+	)
+
+	AddDeadlineReader(&pipe, conn, 16, time.Second)
+
+	values := make(chan interface{})
+
+	pipe.AddOutput(
+		func(ctx context.Context, value interface{}) error {
+			values <- value
+			return nil
+		},
+	)
+
+	pipe.Start(context.Background(), time.Millisecond)
+
+	if v := string((<-values).([]byte)); v != "hello" {
+		t.Fatal("unexpected value", v)
+	}
+
+	pipe.Stop()
+	<-pipe.Done()
+
+	if conn.numReadDeadlines() == 0 {
+		t.Fatal("expected at least one read deadline to be set")
+	}
+}
+
+func TestAddDeadlineWriter_setsDeadline(t *testing.T) {
+	var (
+		pipe Pipe
+		conn = &deadlineConn{w: &bytes.Buffer{}}
+	)
+
+	pipe.AddInput(
+		func(ctx context.Context) (interface{}, bool, error) {
+			return []byte("x"), true, nil
+		},
+	)
+
+	AddDeadlineWriter(&pipe, conn, time.Second)
+
+	pipe.Start(context.Background(), time.Millisecond)
+
+	for conn.numWriteDeadlines() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	pipe.Stop()
+	<-pipe.Done()
+
+	if err := pipe.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,267 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package faucet
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// Monitor tracks transfer statistics for a Pipe over its lifetime, see Pipe.Monitor. It is safe for concurrent
+	// use, including while the monitored Pipe's worker is running.
+	Monitor struct {
+		mutex sync.Mutex
+
+		active     bool
+		start      time.Time
+		lastSample time.Time
+		smoothing  float64
+
+		samples  int64
+		count    int64
+		curRate  float64
+		avgRate  float64
+		peakRate float64
+
+		perInput  []InputStat
+		perOutput []OutputStat
+	}
+
+	// InputStat holds per-input counters tracked by a Monitor, indexed as per Pipe.AddInput, see Status.PerInput.
+	InputStat struct {
+		// Polls is the number of times this input was polled.
+		Polls int64
+		// Values is the number of polls that produced a value (ok == true).
+		Values int64
+		// Errors is the number of polls that returned an error.
+		Errors int64
+	}
+
+	// OutputStat holds per-output counters tracked by a Monitor, indexed as per Pipe.AddOutput, see Status.PerOutput.
+	OutputStat struct {
+		// Success is the number of fan-outs that completed without error.
+		Success int64
+		// Errors is the number of fan-outs that returned an error, including timeouts.
+		Errors int64
+	}
+
+	// Status is a point-in-time snapshot of a Monitor's statistics, see Monitor.Status.
+	Status struct {
+		// Active indicates whether the monitored Pipe's worker is currently running.
+		Active bool
+		// Start is the time the monitored Pipe's worker was last started.
+		Start time.Time
+		// Samples is the number of times the exponential moving average has been updated.
+		Samples int64
+		// Count is the total number of values successfully transferred, i.e. polled from an input and, if any
+		// outputs are registered, fanned out to all of them without error.
+		Count int64
+		// CurRate is the most recent instantaneous values-per-second sample.
+		CurRate float64
+		// AvgRate is the exponential moving average of values-per-second.
+		AvgRate float64
+		// PeakRate is the highest CurRate observed since the last Reset.
+		PeakRate float64
+		// PerInput holds a counter snapshot for each registered input.
+		PerInput []InputStat
+		// PerOutput holds a counter snapshot for each registered output.
+		PerOutput []OutputStat
+	}
+)
+
+// DefaultMonitorSmoothingFactor is the default Monitor EMA smoothing factor ("a" in
+// rEMA = rEMA*a + rSample*(1-a)), see Monitor.SetSmoothingFactor.
+const DefaultMonitorSmoothingFactor = 0.5
+
+// Monitor returns the Monitor attached to p, creating it (with DefaultMonitorSmoothingFactor) on first call, note
+// it will panic if p is nil. The same Monitor instance is returned for the lifetime of p.
+func (p *Pipe) Monitor() *Monitor {
+	p.ensure()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.monitor == nil {
+		p.monitor = &Monitor{
+			smoothing: DefaultMonitorSmoothingFactor,
+			perInput:  make([]InputStat, len(p.inputs)),
+			perOutput: make([]OutputStat, len(p.outputs)),
+		}
+	}
+
+	return p.monitor
+}
+
+// SetSmoothingFactor sets the EMA smoothing factor ("a" in rEMA = rEMA*a + rSample*(1-a)), note it will panic if m
+// is nil. Values are typically in the range [0, 1), the default is DefaultMonitorSmoothingFactor.
+func (m *Monitor) SetSmoothingFactor(a float64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.smoothing = a
+}
+
+// Reset clears all counters and the EMA state, without detaching the Monitor from its Pipe.
+func (m *Monitor) Reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.start = time.Time{}
+	m.lastSample = time.Time{}
+	m.samples = 0
+	m.count = 0
+	m.curRate = 0
+	m.avgRate = 0
+	m.peakRate = 0
+
+	// cleared with atomic stores, rather than struct-literal assignment, since the worker may concurrently be
+	// updating these same counters via atomic.AddInt64 in recordInputPoll et al., without holding m.mutex
+	for i := range m.perInput {
+		atomic.StoreInt64(&m.perInput[i].Polls, 0)
+		atomic.StoreInt64(&m.perInput[i].Values, 0)
+		atomic.StoreInt64(&m.perInput[i].Errors, 0)
+	}
+
+	for i := range m.perOutput {
+		atomic.StoreInt64(&m.perOutput[i].Success, 0)
+		atomic.StoreInt64(&m.perOutput[i].Errors, 0)
+	}
+}
+
+// Status returns a snapshot of the Monitor's current statistics.
+func (m *Monitor) Status() Status {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	perInput := make([]InputStat, len(m.perInput))
+	for i := range m.perInput {
+		perInput[i] = InputStat{
+			Polls:  atomic.LoadInt64(&m.perInput[i].Polls),
+			Values: atomic.LoadInt64(&m.perInput[i].Values),
+			Errors: atomic.LoadInt64(&m.perInput[i].Errors),
+		}
+	}
+
+	perOutput := make([]OutputStat, len(m.perOutput))
+	for i := range m.perOutput {
+		perOutput[i] = OutputStat{
+			Success: atomic.LoadInt64(&m.perOutput[i].Success),
+			Errors:  atomic.LoadInt64(&m.perOutput[i].Errors),
+		}
+	}
+
+	return Status{
+		Active:    m.active,
+		Start:     m.start,
+		Samples:   m.samples,
+		Count:     m.count,
+		CurRate:   m.curRate,
+		AvgRate:   m.avgRate,
+		PeakRate:  m.peakRate,
+		PerInput:  perInput,
+		PerOutput: perOutput,
+	}
+}
+
+// onStart marks the Monitor active, called from Pipe.Start, guarded by p.mutex.
+func (m *Monitor) onStart(now time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.active = true
+	m.start = now
+	m.lastSample = now
+}
+
+// onStop marks the Monitor inactive, called from the Pipe worker's exit path.
+func (m *Monitor) onStop() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.active = false
+}
+
+// growInputs grows perInput to length n if required, called from AddInput/AddInputWithTimeout, guarded by p.mutex.
+func (m *Monitor) growInputs(n int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for len(m.perInput) < n {
+		m.perInput = append(m.perInput, InputStat{})
+	}
+}
+
+// growOutputs grows perOutput to length n if required, called from AddOutput/AddOutputWithTimeout, guarded by
+// p.mutex.
+func (m *Monitor) growOutputs(n int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for len(m.perOutput) < n {
+		m.perOutput = append(m.perOutput, OutputStat{})
+	}
+}
+
+// recordInputPoll, recordInputValue and recordInputError are called from the worker loop, while p.mutex is held,
+// for every poll of input i.
+func (m *Monitor) recordInputPoll(i int) {
+	atomic.AddInt64(&m.perInput[i].Polls, 1)
+}
+
+func (m *Monitor) recordInputValue(i int) {
+	atomic.AddInt64(&m.perInput[i].Values, 1)
+}
+
+func (m *Monitor) recordInputError(i int) {
+	atomic.AddInt64(&m.perInput[i].Errors, 1)
+}
+
+// recordOutputSuccess and recordOutputError are called from the worker loop's output goroutines for every
+// completed fan-out to output i.
+func (m *Monitor) recordOutputSuccess(i int) {
+	atomic.AddInt64(&m.perOutput[i].Success, 1)
+}
+
+func (m *Monitor) recordOutputError(i int) {
+	atomic.AddInt64(&m.perOutput[i].Errors, 1)
+}
+
+// recordTransfer is called from the worker loop, while p.mutex is held, once per tick that successfully transfers
+// a value (polled, and fanned out to all outputs without error), updating the EMA and peak rate.
+func (m *Monitor) recordTransfer(now time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	elapsed := now.Sub(m.lastSample).Seconds()
+	if elapsed <= 0 {
+		elapsed = 1
+	}
+
+	rSample := 1 / elapsed
+
+	m.curRate = rSample
+	m.avgRate = m.avgRate*m.smoothing + rSample*(1-m.smoothing)
+	if m.curRate > m.peakRate {
+		m.peakRate = m.curRate
+	}
+
+	m.samples++
+	m.count++
+	m.lastSample = now
+}
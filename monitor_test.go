@@ -0,0 +1,162 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package faucet
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPipe_Monitor_success(t *testing.T) {
+	var pipe Pipe
+
+	pipe.AddInput(
+		func(ctx context.Context) (interface{}, bool, error) {
+			return 1, true, nil
+		},
+	)
+
+	pipe.AddOutput(
+		func(ctx context.Context, value interface{}) error {
+			return nil
+		},
+	)
+
+	mon := pipe.Monitor()
+
+	pipe.Start(context.Background(), time.Millisecond*50)
+
+	time.Sleep(time.Millisecond * 220)
+
+	pipe.Stop()
+	<-pipe.Done()
+
+	if err := pipe.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	status := mon.Status()
+
+	if status.Active {
+		t.Fatal("expected not active after stop")
+	}
+
+	if status.Count < 2 {
+		t.Fatal("unexpected count", status.Count)
+	}
+
+	if status.Samples != status.Count {
+		t.Fatal("unexpected samples", status.Samples, "count", status.Count)
+	}
+
+	if len(status.PerInput) != 1 || status.PerInput[0].Values != status.Count {
+		t.Fatal("unexpected per-input stats", status.PerInput)
+	}
+
+	if len(status.PerOutput) != 1 || status.PerOutput[0].Success != status.Count {
+		t.Fatal("unexpected per-output stats", status.PerOutput)
+	}
+
+	if status.AvgRate <= 0 {
+		t.Fatal("expected a positive average rate")
+	}
+}
+
+func TestPipe_Monitor_outputError(t *testing.T) {
+	var pipe Pipe
+
+	pipe.AddInput(
+		func(ctx context.Context) (interface{}, bool, error) {
+			return 1, true, nil
+		},
+	)
+
+	pipe.AddOutput(
+		func(ctx context.Context, value interface{}) error {
+			return errors.New("some_error")
+		},
+	)
+
+	mon := pipe.Monitor()
+
+	pipe.Start(context.Background(), time.Millisecond)
+
+	<-pipe.Done()
+
+	status := mon.Status()
+
+	if status.Count != 0 {
+		t.Fatal("unexpected count", status.Count)
+	}
+
+	if status.PerOutput[0].Errors != 1 {
+		t.Fatal("unexpected per-output stats", status.PerOutput)
+	}
+}
+
+func TestPipe_Monitor_reset(t *testing.T) {
+	var pipe Pipe
+
+	pipe.AddInput(
+		func(ctx context.Context) (interface{}, bool, error) {
+			return 1, true, nil
+		},
+	)
+
+	mon := pipe.Monitor()
+
+	pipe.Start(context.Background(), time.Millisecond*50)
+
+	time.Sleep(time.Millisecond * 120)
+
+	pipe.Stop()
+	<-pipe.Done()
+
+	if mon.Status().Count == 0 {
+		t.Fatal("expected a nonzero count before reset")
+	}
+
+	mon.Reset()
+
+	status := mon.Status()
+
+	if status.Count != 0 || status.Samples != 0 || status.AvgRate != 0 || status.PerInput[0].Values != 0 {
+		t.Fatal("unexpected status after reset", status)
+	}
+}
+
+func TestPipe_Monitor_addAfterMonitor(t *testing.T) {
+	var pipe Pipe
+
+	mon := pipe.Monitor()
+
+	if len(mon.Status().PerInput) != 0 {
+		t.Fatal("expected no inputs yet")
+	}
+
+	pipe.AddInput(
+		func(ctx context.Context) (interface{}, bool, error) {
+			return nil, false, nil
+		},
+	)
+
+	if len(mon.Status().PerInput) != 1 {
+		t.Fatal("expected growth to track AddInput")
+	}
+}
@@ -24,7 +24,15 @@ import (
 )
 
 // AddInput adds fn to the pipe as input, note it will panic if fn or the pipe are nil.
+// The input is subject to Pipe's default timeout, if set, see SetDefaultTimeout and AddInputWithTimeout.
 func (p *Pipe) AddInput(fn func(ctx context.Context) (interface{}, bool, error)) {
+	p.AddInputWithTimeout(fn, 0)
+}
+
+// AddInputWithTimeout adds fn to the pipe as input, wrapping each call in a fresh context.WithTimeout derived from
+// the pipe's context, note it will panic if fn or the pipe are nil. A timeout <= 0 defers to the pipe's default
+// timeout (see SetDefaultTimeout), which may itself be unset, in which case the call is unbounded.
+func (p *Pipe) AddInputWithTimeout(fn func(ctx context.Context) (interface{}, bool, error), timeout time.Duration) {
 	p.ensure()
 
 	if fn == nil {
@@ -34,11 +42,49 @@ func (p *Pipe) AddInput(fn func(ctx context.Context) (interface{}, bool, error))
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
-	p.inputs = append(p.inputs, fn)
+	p.inputs = append(p.inputs, pipeInput{fn: fn, timeout: timeout})
+
+	if p.monitor != nil {
+		p.monitor.growInputs(len(p.inputs))
+	}
+}
+
+// AddInputWithWeight adds fn to the pipe as input, with a select weight of weight (see Weighted), note it will
+// panic if fn or the pipe are nil, or weight is not greater than zero. The input is subject to Pipe's default
+// timeout, if set, like AddInput.
+func (p *Pipe) AddInputWithWeight(fn func(ctx context.Context) (interface{}, bool, error), weight int) {
+	p.ensure()
+
+	if fn == nil {
+		panic(errors.New("faucet.AddInputWithWeight nil fn"))
+	}
+
+	if weight <= 0 {
+		panic(errors.New("faucet.AddInputWithWeight weight <= 0"))
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.inputs = append(p.inputs, pipeInput{fn: fn, weight: weight})
+
+	if p.monitor != nil {
+		p.monitor.growInputs(len(p.inputs))
+	}
 }
 
 // AddOutput adds fn to the pipe as output, note it will panic if fn or the pipe are nil.
+// The output is subject to Pipe's default timeout, if set, see SetDefaultTimeout and AddOutputWithTimeout.
 func (p *Pipe) AddOutput(fn func(ctx context.Context, value interface{}) error) {
+	p.AddOutputWithTimeout(fn, 0)
+}
+
+// AddOutputWithTimeout adds fn to the pipe as output, wrapping each call in a fresh context.WithTimeout derived from
+// the pipe's context, note it will panic if fn or the pipe are nil. A timeout <= 0 defers to the pipe's default
+// timeout (see SetDefaultTimeout), which may itself be unset, in which case the call is unbounded.
+// If the deadline is exceeded, the call is abandoned (its eventual result, if any, is discarded) and the tick
+// proceeds with a faucet.Pipe.output.N timeout error instead of waiting for it to return.
+func (p *Pipe) AddOutputWithTimeout(fn func(ctx context.Context, value interface{}) error, timeout time.Duration) {
 	p.ensure()
 
 	if fn == nil {
@@ -48,7 +94,38 @@ func (p *Pipe) AddOutput(fn func(ctx context.Context, value interface{}) error)
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
-	p.outputs = append(p.outputs, fn)
+	p.outputs = append(p.outputs, pipeOutput{fn: fn, timeout: timeout})
+
+	if p.monitor != nil {
+		p.monitor.growOutputs(len(p.outputs))
+	}
+}
+
+// SetDefaultTimeout sets the pipe-wide default per-call timeout, applied to any input or output that was not given
+// its own timeout via AddInputWithTimeout or AddOutputWithTimeout, note it will panic if the pipe is nil.
+// A value <= 0 disables the default, leaving such calls unbounded.
+func (p *Pipe) SetDefaultTimeout(timeout time.Duration) {
+	p.ensure()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.defaultTimeout = timeout
+}
+
+// SetSelectStrategy overrides the SelectStrategy used to order input polling each tick, the default being
+// RoundRobin, note it will panic if strategy or the pipe are nil.
+func (p *Pipe) SetSelectStrategy(strategy SelectStrategy) {
+	p.ensure()
+
+	if strategy == nil {
+		panic(errors.New("faucet.Pipe.SetSelectStrategy nil strategy"))
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.selectStrategy = strategy
 }
 
 // Err returns any internal error, which will be set on pipe worker failure, note it panics if the pipe is nil.
@@ -75,7 +152,8 @@ func (p *Pipe) Done() <-chan struct{} {
 // Start initialises the pipe worker with the provided context and duration, each pipe may be started exactly once,
 // and it will panic if ctx or the pipe are nil, or rate is note greater than zero.
 // Note that it will tick immediately (async), unlike time.Ticker.
-func (p *Pipe) Start(ctx context.Context, rate time.Duration) {
+// Any Option values provided are applied, in order, before the worker is started, see WithTickerFunc.
+func (p *Pipe) Start(ctx context.Context, rate time.Duration, opts ...Option) {
 	p.ensure()
 
 	if ctx == nil {
@@ -93,15 +171,32 @@ func (p *Pipe) Start(ctx context.Context, rate time.Duration) {
 		panic(errors.New("faucet.Pipe.Start already started"))
 	}
 
-	p.ticker = time.NewTicker(rate)
+	for _, opt := range opts {
+		if opt != nil {
+			opt(p)
+		}
+	}
+
+	newTicker := p.newTicker
+	if newTicker == nil {
+		newTicker = NewTicker
+	}
+
+	p.ticker = newTicker(rate)
 	p.ctx, p.cancel = context.WithCancel(ctx)
 
+	if p.monitor != nil {
+		p.monitor.onStart(time.Now())
+	}
+
 	go p.worker()
 	go p.cleanup()
 }
 
 // Stop will prevent further ticks from succeeding (that are not already in progress), note it will panic if the
-// pipe is nil, or hasn't already been started.
+// pipe is nil, or hasn't already been started. It also cancels the pipe's internal context, so that a call to an
+// input or output fn that is already in progress (and respects ctx, e.g. by selecting on ctx.Done()) unwinds
+// promptly, rather than only being noticed by the worker between ticks.
 func (p *Pipe) Stop() {
 	p.ensure()
 
@@ -111,9 +206,25 @@ func (p *Pipe) Stop() {
 
 	p.close.Do(func() {
 		close(p.stop)
+		// cancel promptly, without taking p.mutex, since doTick holds it for the duration of a tick, including
+		// any blocked input/output call this is meant to unstick
+		p.cancel()
 	})
 }
 
+// stopped reports whether Stop has been called (i.e. p.stop is closed), without blocking. Since Stop cancels
+// p.ctx as well as closing p.stop (see Stop), this distinguishes a deliberate Stop from the pipe's own context
+// being canceled for some other reason (e.g. the ctx passed to Start/StartBucket was canceled by the caller),
+// which should still be reported via Err.
+func (p *Pipe) stopped() bool {
+	select {
+	case <-p.stop:
+		return true
+	default:
+		return false
+	}
+}
+
 func (p *Pipe) ensure() {
 	if p == nil {
 		panic(errors.New("faucet.Pipe nil receiver"))
@@ -137,6 +248,11 @@ func (p *Pipe) cleanup() {
 func (p *Pipe) worker() {
 	defer close(p.done)
 	defer p.cancel()
+	defer func() {
+		if p.monitor != nil {
+			p.monitor.onStop()
+		}
+	}()
 
 	var (
 		err       error
@@ -156,133 +272,265 @@ func (p *Pipe) worker() {
 	}()
 
 	for i := 0; ; i++ {
+		if p.stopped() {
+			return
+		}
+
 		err = p.ctx.Err()
 
 		if err != nil {
+			// Stop may have canceled p.ctx in the gap since the p.stopped() check above; recheck so that's
+			// reported as a clean stop, not a context error
+			if p.stopped() {
+				err = nil
+				return
+			}
 			err = fmt.Errorf("faucet.Pipe context error: %v", err)
 			return
 		}
 
-		// tick checks the input(s)
-		tick := func() bool {
-			p.mutex.Lock()
-			defer p.mutex.Unlock()
+		// the first iteration will immediately tick (so as to start immediately)
+		if first {
+			first = false
+			if _, ok := p.doTick(i, &lastInput, &err); !ok {
+				return
+			}
+			// next iteration (so i increments)
+			continue
+		}
 
-			// double check if we are stopped (the channel might still have a tick after close)
-			select {
-			case <-p.stop:
-				return false
+		select {
+		case <-p.stop:
+			// stop has been called
+			return
 
-			default:
+		case <-p.ctx.Done():
+			// context has been canceled: if that was because Stop was called (which also cancels p.ctx, to
+			// unstick any blocked input/output call), this is a clean stop, not an error
+			if p.stopped() {
+				return
 			}
+			err = fmt.Errorf("faucet.Pipe context error: %v", p.ctx.Err())
+			return
 
-			inputLength, outputLength := len(p.inputs), len(p.outputs)
+		case <-p.ticker.C():
+			// ticker has been triggered, poll inputs
+			if _, ok := p.doTick(i, &lastInput, &err); !ok {
+				return
+			}
+		}
+	}
+}
 
-			for j := 0; j < inputLength; j++ {
-				err = p.ctx.Err()
+// doTick polls the inputs (in the order given by the Pipe's SelectStrategy, defaulting to RoundRobin) and, on the
+// first that produces a value, fans it out to all outputs, recording the outcome via *err and the monitor (if
+// attached). It returns moved=true if a value was successfully polled and fanned out (i.e. a transfer actually
+// happened), and ok=false if the worker loop should stop (either because Stop was called, or *err was set).
+func (p *Pipe) doTick(i int, lastInput *int, err *error) (bool, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
 
-				if err != nil {
-					err = fmt.Errorf("faucet.Pipe context error: %v", err)
-					return false
-				}
+	// double check if we are stopped (the channel might still have a tick after close)
+	select {
+	case <-p.stop:
+		return false, false
 
-				var (
-					value interface{}
-					ok    bool
-				)
+	default:
+	}
 
-				lastInput = (i + j) % inputLength
+	inputLength, outputLength := len(p.inputs), len(p.outputs)
 
-				value, ok, err = p.inputs[lastInput](p.ctx)
+	strategy := p.selectStrategy
+	if strategy == nil {
+		strategy = RoundRobin{}
+	}
 
-				if err != nil {
-					// input error, will exit with error
-					err = fmt.Errorf("faucet.Pipe.input.%d error: %v", lastInput, err)
-					return false
-				}
+	weights := make([]int, inputLength)
+	for idx, input := range p.inputs {
+		weights[idx] = input.weight
+		if weights[idx] <= 0 {
+			weights[idx] = 1
+		}
+	}
 
-				if !ok {
-					// try the next input
-					continue
-				}
+	order, advance := strategy.Next(&SelectState{Tick: i, InputLength: inputLength, Weights: weights})
 
-				// fetched an input, fan out the output
+	for _, idx := range order {
+		*err = p.ctx.Err()
 
-				if outputLength == 0 {
-					// nothing to fan out to, done for this tick
-					return true
-				}
+		if *err != nil {
+			if p.stopped() {
+				// Stop was called mid-tick (it also cancels p.ctx, to unstick any blocked input/output call);
+				// a clean stop, not an error
+				*err = nil
+				advance(-1)
+				return false, false
+			}
+			*err = fmt.Errorf("faucet.Pipe context error: %v", *err)
+			advance(-1)
+			return false, false
+		}
 
-				errs := make(chan error, outputLength)
+		var (
+			value interface{}
+			ok    bool
+		)
 
-				for x, output := range p.outputs {
-					go func(x int, output func(context.Context, interface{}) error) {
-						var err error
+		*lastInput = idx
 
-						defer func() {
-							if r := recover(); r != nil {
-								err = fmt.Errorf("faucet.Pipe.output.%d recovered from panic (%T): %+v", x, r, r)
-							}
+		input := p.inputs[*lastInput]
+		inputCtx := p.ctx
 
-							errs <- err
-						}()
+		if p.monitor != nil {
+			p.monitor.recordInputPoll(*lastInput)
+		}
 
-						err = output(p.ctx, value)
+		timeout := input.timeout
+		if timeout <= 0 {
+			timeout = p.defaultTimeout
+		}
 
-						if err != nil {
-							err = fmt.Errorf("faucet.Pipe.output.%d error: %v", x, err)
-						}
-					}(x, output)
-				}
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			inputCtx, cancel = context.WithTimeout(inputCtx, timeout)
+			defer cancel()
+		}
+
+		value, ok, *err = input.fn(inputCtx)
+
+		if *err != nil {
+			if timeout > 0 && inputCtx.Err() == context.DeadlineExceeded {
+				// input exceeded its deadline, will exit with error
+				*err = fmt.Errorf("faucet.Pipe.input.%d timeout: %v", *lastInput, *err)
+			} else {
+				// input error, will exit with error
+				*err = fmt.Errorf("faucet.Pipe.input.%d error: %v", *lastInput, *err)
+			}
+			if p.monitor != nil {
+				p.monitor.recordInputError(*lastInput)
+			}
+			advance(-1)
+			return false, false
+		}
+
+		if !ok {
+			// try the next input
+			continue
+		}
+
+		advance(*lastInput)
 
-				for x := 0; x < outputLength; x++ {
-					outputErr := <-errs
+		if p.monitor != nil {
+			p.monitor.recordInputValue(*lastInput)
+		}
 
-					if outputErr == nil {
-						continue
+		// fetched an input, fan out the output
+
+		if outputLength == 0 {
+			// nothing to fan out to, done for this tick
+			if p.monitor != nil {
+				p.monitor.recordTransfer(time.Now())
+			}
+			return true, true
+		}
+
+		errs := make(chan error, outputLength)
+
+		for x, output := range p.outputs {
+			timeout := output.timeout
+			if timeout <= 0 {
+				timeout = p.defaultTimeout
+			}
+
+			go func(x int, output func(context.Context, interface{}) error, timeout time.Duration) {
+				var err error
+
+				defer func() {
+					if r := recover(); r != nil {
+						err = fmt.Errorf("faucet.Pipe.output.%d recovered from panic (%T): %+v", x, r, r)
 					}
 
-					if err == nil {
-						err = outputErr
-						continue
+					if p.monitor != nil {
+						if err == nil {
+							p.monitor.recordOutputSuccess(x)
+						} else {
+							p.monitor.recordOutputError(x)
+						}
 					}
 
-					err = fmt.Errorf("%v | %v", err, outputErr)
+					errs <- err
+				}()
+
+				if timeout <= 0 {
+					// no deadline, call directly, matching the original (pre-timeout) behavior
+					err = output(p.ctx, value)
+
+					if err != nil {
+						err = fmt.Errorf("faucet.Pipe.output.%d error: %v", x, err)
+					}
+
+					return
 				}
 
-				// fanned out all output, possibly with errors, done for this tick
-				return err == nil
-			}
+				ctx, cancel := context.WithTimeout(p.ctx, timeout)
+				defer cancel()
+
+				// done is buffered so the inner goroutine never blocks on send, even if abandoned below
+				done := make(chan error, 1)
 
-			// did not retrieve any input (from any input), but no error, done for this tick
-			return true
+				go func() {
+					defer func() {
+						if r := recover(); r != nil {
+							done <- fmt.Errorf("faucet.Pipe.output.%d recovered from panic (%T): %+v", x, r, r)
+						}
+					}()
+
+					done <- output(ctx, value)
+				}()
+
+				select {
+				case err = <-done:
+					if err != nil {
+						err = fmt.Errorf("faucet.Pipe.output.%d error: %v", x, err)
+					}
+
+				case <-ctx.Done():
+					// abandon the still-running call, its eventual result (if any) is discarded
+					if ctx.Err() == context.DeadlineExceeded {
+						err = fmt.Errorf("faucet.Pipe.output.%d timeout: %v", x, ctx.Err())
+					} else {
+						err = fmt.Errorf("faucet.Pipe.output.%d error: %v", x, ctx.Err())
+					}
+				}
+			}(x, output.fn, timeout)
 		}
 
-		// the first iteration will immediately tick (so as to start immediately)
-		if first {
-			first = false
-			if !tick() {
-				return
+		for x := 0; x < outputLength; x++ {
+			outputErr := <-errs
+
+			if outputErr == nil {
+				continue
 			}
-			// next iteration (so i increments)
-			continue
-		}
 
-		select {
-		case <-p.stop:
-			// stop has been called
-			return
+			if *err == nil {
+				*err = outputErr
+				continue
+			}
 
-		case <-p.ctx.Done():
-			// context has been canceled
-			err = fmt.Errorf("faucet.Pipe context error: %v", p.ctx.Err())
-			return
+			*err = fmt.Errorf("%v | %v", *err, outputErr)
+		}
 
-		case <-p.ticker.C:
-			// ticker has been triggered, poll inputs
-			if !tick() {
-				return
+		// fanned out all output, possibly with errors, done for this tick
+		if *err == nil {
+			if p.monitor != nil {
+				p.monitor.recordTransfer(time.Now())
 			}
+			return true, true
 		}
+		return false, false
 	}
+
+	// did not retrieve any input (from any input), but no error, done for this tick
+	advance(-1)
+	return false, true
 }
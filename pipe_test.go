@@ -26,7 +26,10 @@ import (
 )
 
 func ExamplePipe_roundRobinPingPong() {
-	var pipe Pipe
+	var (
+		pipe Pipe
+		lt   = NewLogicalTicker()
+	)
 
 	x := 0
 	y := 0
@@ -46,6 +49,7 @@ func ExamplePipe_roundRobinPingPong() {
 	)
 
 	ch := make(chan struct{})
+	tickDone := make(chan struct{})
 
 	pipe.AddOutput(
 		func(ctx context.Context, value interface{}) error {
@@ -59,16 +63,21 @@ func ExamplePipe_roundRobinPingPong() {
 		func(ctx context.Context, value interface{}) error {
 			<-ch
 			fmt.Printf("pong: %v\n", value)
+			tickDone <- struct{}{}
 			return nil
 		},
 	)
 
-	pipe.Start(context.Background(), time.Millisecond*1000)
+	pipe.Start(context.Background(), time.Hour, WithTickerFunc(func(time.Duration) Ticker { return lt }))
 
-	go func() {
-		time.Sleep(time.Millisecond * 5500)
-		pipe.Stop()
-	}()
+	<-tickDone // the initial, automatic tick
+
+	for i := 0; i < 4; i++ {
+		lt.Advance(time.Hour)
+		<-tickDone
+	}
+
+	pipe.Stop()
 
 	<-pipe.Done()
 
@@ -86,7 +95,10 @@ func ExamplePipe_roundRobinPingPong() {
 }
 
 func ExamplePipe_fallbackInputs() {
-	var pipe Pipe
+	var (
+		pipe Pipe
+		lt   = NewLogicalTicker()
+	)
 
 	pipe.AddInput(
 		func(ctx context.Context) (interface{}, bool, error) {
@@ -124,16 +136,24 @@ func ExamplePipe_fallbackInputs() {
 		},
 	)
 
+	tickDone := make(chan struct{})
+
 	pipe.AddOutput(
 		func(ctx context.Context, value interface{}) error {
 			fmt.Println(value)
+			tickDone <- struct{}{}
 			return nil
 		},
 	)
 
-	pipe.Start(context.Background(), time.Millisecond*50)
+	pipe.Start(context.Background(), time.Hour, WithTickerFunc(func(time.Duration) Ticker { return lt }))
 
-	time.Sleep(time.Second + (time.Millisecond * 25))
+	<-tickDone // the initial, automatic tick
+
+	for i := 0; i < 19; i++ {
+		lt.Advance(time.Hour)
+		<-tickDone
+	}
 
 	pipe.Stop()
 
@@ -484,20 +504,23 @@ func TestPipe_noInput_2(t *testing.T) {
 
 func TestPipe_noOutput(t *testing.T) {
 	var (
-		pipe  Pipe
-		count int
+		pipe   Pipe
+		count  int
+		lt     = NewLogicalTicker()
+		ticked = make(chan struct{})
 	)
 
 	pipe.AddInput(
 		func(ctx context.Context) (interface{}, bool, error) {
 			count++
+			ticked <- struct{}{}
 			return 3, true, nil
 		},
 	)
 
-	pipe.Start(context.Background(), time.Millisecond*150)
+	pipe.Start(context.Background(), time.Hour, WithTickerFunc(func(time.Duration) Ticker { return lt }))
 
-	time.Sleep(time.Millisecond * 200)
+	<-ticked // the initial, automatic tick; lt is never advanced, so no further tick can occur
 
 	pipe.Stop()
 
@@ -551,3 +574,114 @@ func TestPipe_addAfterStart(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestPipe_AddInputWithTimeout_timeout(t *testing.T) {
+	var pipe Pipe
+
+	pipe.AddInputWithTimeout(
+		func(ctx context.Context) (interface{}, bool, error) {
+			<-ctx.Done()
+			return nil, false, ctx.Err()
+		},
+		time.Millisecond*10,
+	)
+
+	pipe.Start(context.Background(), time.Hour)
+
+	<-pipe.Done()
+
+	err := pipe.Err()
+
+	if err == nil || err.Error() != "faucet.Pipe.input.0 timeout: context deadline exceeded" {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestPipe_AddOutputWithTimeout_timeout(t *testing.T) {
+	var pipe Pipe
+
+	pipe.AddInput(
+		func(ctx context.Context) (interface{}, bool, error) {
+			return "some_value", true, nil
+		},
+	)
+
+	blocked := make(chan struct{})
+
+	pipe.AddOutputWithTimeout(
+		func(ctx context.Context, value interface{}) error {
+			<-ctx.Done()
+			close(blocked)
+			return nil
+		},
+		time.Millisecond*10,
+	)
+
+	pipe.Start(context.Background(), time.Hour)
+
+	<-pipe.Done()
+
+	// the abandoned call must still have been allowed to observe the deadline
+	<-blocked
+
+	err := pipe.Err()
+
+	if err == nil || err.Error() != "faucet.Pipe.output.0 timeout: context deadline exceeded" {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestPipe_SetDefaultTimeout(t *testing.T) {
+	var pipe Pipe
+
+	pipe.SetDefaultTimeout(time.Millisecond * 10)
+
+	pipe.AddInput(
+		func(ctx context.Context) (interface{}, bool, error) {
+			<-ctx.Done()
+			return nil, false, ctx.Err()
+		},
+	)
+
+	pipe.Start(context.Background(), time.Hour)
+
+	<-pipe.Done()
+
+	err := pipe.Err()
+
+	if err == nil || err.Error() != "faucet.Pipe.input.0 timeout: context deadline exceeded" {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestPipe_AddInputWithTimeout_overridesDefault(t *testing.T) {
+	var (
+		pipe  Pipe
+		count int
+	)
+
+	pipe.SetDefaultTimeout(time.Hour)
+
+	pipe.AddInputWithTimeout(
+		func(ctx context.Context) (interface{}, bool, error) {
+			<-ctx.Done()
+			count++
+			return nil, false, ctx.Err()
+		},
+		time.Millisecond*10,
+	)
+
+	pipe.Start(context.Background(), time.Hour)
+
+	<-pipe.Done()
+
+	if count != 1 {
+		t.Fatal("unexpected count", count)
+	}
+
+	err := pipe.Err()
+
+	if err == nil || err.Error() != "faucet.Pipe.input.0 timeout: context deadline exceeded" {
+		t.Fatal("unexpected error", err)
+	}
+}
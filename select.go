@@ -0,0 +1,151 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package faucet
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+type (
+	// SelectStrategy determines the order in which a Pipe's inputs are polled, each tick, see
+	// Pipe.SetSelectStrategy. The default, used if none is set, is RoundRobin.
+	SelectStrategy interface {
+		// Next returns order, a permutation of 0..state.InputLength-1, in the order inputs should be polled for
+		// this tick, and advance, which the caller must invoke exactly once, with the index of the input that
+		// produced a value (ok=true), or -1 if none did, so the strategy can update any internal state ahead of
+		// the next call to Next.
+		Next(state *SelectState) (order []int, advance func(picked int))
+	}
+
+	// SelectState carries the per-tick context a SelectStrategy needs to compute an input poll order, see
+	// SelectStrategy.Next.
+	SelectState struct {
+		// Tick is the current worker iteration (the i of Pipe.worker's loop), monotonically increasing.
+		Tick int
+
+		// InputLength is the number of registered inputs, order must be a permutation of 0..InputLength-1.
+		InputLength int
+
+		// Weights holds the configured weight for each input, in registration order, see AddInputWithWeight.
+		// Inputs registered without an explicit weight (e.g. via AddInput) default to 1.
+		Weights []int
+	}
+
+	// RoundRobin is the default SelectStrategy: it offers the inputs starting from index Tick % InputLength,
+	// wrapping around, so the input checked first rotates by one every tick, matching Pipe's original (pre-
+	// SelectStrategy) hardcoded behavior.
+	RoundRobin struct{}
+
+	// Priority is a SelectStrategy that always tries inputs in registration order, so input 0 dominates for as
+	// long as it keeps returning ok=true, falling through to later inputs only when earlier ones don't.
+	Priority struct{}
+
+	// Weighted is a SelectStrategy that favors inputs in proportion to their configured weight (see
+	// AddInputWithWeight), using a deficit-round-robin counter: every tick, each input's deficit is increased by
+	// its weight, then inputs are tried in descending order of deficit (ties broken by registration order); the
+	// input that produces a value has its deficit reset to 0. This lets heavier inputs be visited more often,
+	// without starving lighter ones. The zero value is ready to use. A Weighted tracks deficits for a single Pipe's
+	// input count; it is not meant to be shared across Pipes with differing numbers of inputs.
+	Weighted struct {
+		mutex    sync.Mutex
+		deficits []int
+	}
+
+	// Random is a SelectStrategy that shuffles the input order on every tick, using math/rand's (thread-safe)
+	// global source.
+	Random struct{}
+)
+
+// NewWeighted constructs a Weighted strategy, ready for use. Note this is equivalent to new(Weighted), it exists
+// for parity with this package's other constructors.
+func NewWeighted() *Weighted {
+	return &Weighted{}
+}
+
+// Next implements SelectStrategy.
+func (RoundRobin) Next(state *SelectState) ([]int, func(int)) {
+	order := make([]int, state.InputLength)
+	for j := range order {
+		order[j] = (state.Tick + j) % state.InputLength
+	}
+	return order, noopAdvance
+}
+
+// Next implements SelectStrategy.
+func (Priority) Next(state *SelectState) ([]int, func(int)) {
+	order := make([]int, state.InputLength)
+	for j := range order {
+		order[j] = j
+	}
+	return order, noopAdvance
+}
+
+// Next implements SelectStrategy.
+func (w *Weighted) Next(state *SelectState) ([]int, func(int)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.deficits) != state.InputLength {
+		w.deficits = make([]int, state.InputLength)
+	}
+
+	for i := range w.deficits {
+		weight := 1
+		if i < len(state.Weights) && state.Weights[i] > 0 {
+			weight = state.Weights[i]
+		}
+		w.deficits[i] += weight
+	}
+
+	order := make([]int, state.InputLength)
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		return w.deficits[order[a]] > w.deficits[order[b]]
+	})
+
+	return order, func(picked int) {
+		if picked < 0 {
+			return
+		}
+
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+
+		w.deficits[picked] = 0
+	}
+}
+
+// Next implements SelectStrategy.
+func (Random) Next(state *SelectState) ([]int, func(int)) {
+	order := make([]int, state.InputLength)
+	for i := range order {
+		order[i] = i
+	}
+
+	rand.Shuffle(state.InputLength, func(i, j int) {
+		order[i], order[j] = order[j], order[i]
+	})
+
+	return order, noopAdvance
+}
+
+func noopAdvance(int) {}
@@ -0,0 +1,162 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package faucet
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRoundRobin_Next(t *testing.T) {
+	var strategy RoundRobin
+
+	order, advance := strategy.Next(&SelectState{Tick: 0, InputLength: 3})
+	if !reflect.DeepEqual(order, []int{0, 1, 2}) {
+		t.Fatal("unexpected order", order)
+	}
+	advance(-1) // must not panic
+
+	order, advance = strategy.Next(&SelectState{Tick: 2, InputLength: 3})
+	if !reflect.DeepEqual(order, []int{2, 0, 1}) {
+		t.Fatal("unexpected order", order)
+	}
+	advance(2) // must not panic, and must not affect future order (stateless)
+
+	order, _ = strategy.Next(&SelectState{Tick: 2, InputLength: 3})
+	if !reflect.DeepEqual(order, []int{2, 0, 1}) {
+		t.Fatal("unexpected order", order)
+	}
+}
+
+func TestPriority_Next(t *testing.T) {
+	var strategy Priority
+
+	for tick := 0; tick < 3; tick++ {
+		order, advance := strategy.Next(&SelectState{Tick: tick, InputLength: 4})
+		if !reflect.DeepEqual(order, []int{0, 1, 2, 3}) {
+			t.Fatal("unexpected order", order)
+		}
+		advance(1)
+	}
+}
+
+func TestWeighted_Next(t *testing.T) {
+	strategy := NewWeighted()
+
+	weights := []int{1, 2, 1}
+
+	// simulate a long run where every tick's first-in-order input is the one that's picked, and tally how often
+	// each input ends up first: the counts should roughly track the weights (1:2:1, i.e. input 1 about twice as
+	// often as either of its peers).
+	var firstCounts [3]int
+	for tick := 0; tick < 400; tick++ {
+		order, advance := strategy.Next(&SelectState{Tick: tick, InputLength: 3, Weights: weights})
+		firstCounts[order[0]]++
+		advance(order[0])
+	}
+
+	if firstCounts[1] <= firstCounts[0] || firstCounts[1] <= firstCounts[2] {
+		t.Fatal("expected input 1 (weight 2) to lead more often than its peers", firstCounts)
+	}
+}
+
+func TestRandom_Next(t *testing.T) {
+	var strategy Random
+
+	order, advance := strategy.Next(&SelectState{Tick: 0, InputLength: 5})
+	advance(-1)
+
+	seen := make(map[int]bool, len(order))
+	for _, idx := range order {
+		seen[idx] = true
+	}
+	if len(seen) != 5 {
+		t.Fatal("expected a permutation of 0..4", order)
+	}
+}
+
+func TestPipe_SetSelectStrategy_nil(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+
+	var pipe Pipe
+	pipe.SetSelectStrategy(nil)
+}
+
+func TestPipe_AddInputWithWeight_invalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+
+	var pipe Pipe
+	pipe.AddInputWithWeight(func(ctx context.Context) (interface{}, bool, error) {
+		return nil, false, nil
+	}, 0)
+}
+
+func ExamplePipe_priorityStrategy() {
+	var pipe Pipe
+
+	pipe.SetSelectStrategy(Priority{})
+
+	// control, always has a value: should dominate over the bulk input below
+	pipe.AddInput(
+		func(ctx context.Context) (interface{}, bool, error) {
+			return "control", true, nil
+		},
+	)
+
+	pipe.AddInput(
+		func(ctx context.Context) (interface{}, bool, error) {
+			return "bulk", true, nil
+		},
+	)
+
+	values := make(chan interface{}, 3)
+
+	pipe.AddOutput(
+		func(ctx context.Context, value interface{}) error {
+			values <- value
+			return nil
+		},
+	)
+
+	pipe.Start(context.Background(), time.Millisecond*10)
+	defer pipe.Stop()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case v := <-values:
+			fmt.Println(v)
+		case <-time.After(time.Second):
+			panic("timed out waiting for value")
+		}
+	}
+
+	// Output:
+	// control
+	// control
+	// control
+}
@@ -0,0 +1,107 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package faucet
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// Ticker abstracts the part of time.Ticker that Pipe depends on, allowing it to be substituted (e.g. with
+	// LogicalTicker) so that scheduling behavior can be tested deterministically.
+	Ticker interface {
+		// C returns the channel on which ticks are delivered.
+		C() <-chan time.Time
+
+		// Stop stops the ticker, it must be safe to call more than once.
+		Stop()
+	}
+
+	// TickerFunc constructs a Ticker for a given rate, it is the extension point used by Pipe in place of a
+	// hardcoded time.NewTicker call, see WithTickerFunc.
+	TickerFunc func(rate time.Duration) Ticker
+
+	// Option configures a Pipe, see the functions returning Option for the available configuration.
+	Option func(p *Pipe)
+
+	// LogicalTicker is a Ticker implementation with no relation to real time, it only ticks in response to calls to
+	// Advance or Fire, which makes it suitable for driving a Pipe worker loop step-by-step in tests.
+	LogicalTicker struct {
+		mutex sync.Mutex
+		ch    chan time.Time
+		now   time.Time
+	}
+
+	realTicker struct {
+		*time.Ticker
+	}
+)
+
+// WithTickerFunc overrides the TickerFunc used by Start to construct the Pipe's Ticker, the default being NewTicker
+// (a thin wrapper around time.NewTicker). It is intended for injecting a LogicalTicker in tests.
+func WithTickerFunc(fn TickerFunc) Option {
+	return func(p *Pipe) {
+		p.newTicker = fn
+	}
+}
+
+// NewTicker is the default TickerFunc used by Pipe, it wraps time.NewTicker.
+func NewTicker(rate time.Duration) Ticker {
+	return realTicker{time.NewTicker(rate)}
+}
+
+func (r realTicker) C() <-chan time.Time {
+	return r.Ticker.C
+}
+
+// NewLogicalTicker constructs a LogicalTicker, ready for use, note it never ticks on its own, see Advance and Fire.
+func NewLogicalTicker() *LogicalTicker {
+	return &LogicalTicker{ch: make(chan time.Time, 1)}
+}
+
+// C returns the channel on which ticks are delivered, implementing Ticker.
+func (l *LogicalTicker) C() <-chan time.Time {
+	return l.ch
+}
+
+// Stop is a no-op, implementing Ticker, note it does not close the channel returned by C.
+func (l *LogicalTicker) Stop() {}
+
+// Advance moves the logical clock forward by d, then delivers a tick carrying the new logical time, note it will
+// not block if a previously delivered tick has not yet been received.
+func (l *LogicalTicker) Advance(d time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.now = l.now.Add(d)
+	l.fire()
+}
+
+// Fire delivers a tick carrying the current logical time, without advancing the clock, note it will not block if a
+// previously delivered tick has not yet been received.
+func (l *LogicalTicker) Fire() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.fire()
+}
+
+func (l *LogicalTicker) fire() {
+	select {
+	case l.ch <- l.now:
+	default:
+	}
+}
@@ -0,0 +1,116 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package faucet
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLogicalTicker_advance(t *testing.T) {
+	lt := NewLogicalTicker()
+
+	select {
+	case <-lt.C():
+		t.Fatal("unexpected tick")
+	default:
+	}
+
+	lt.Advance(time.Second)
+
+	select {
+	case tick := <-lt.C():
+		if !tick.Equal(time.Time{}.Add(time.Second)) {
+			t.Fatal("unexpected tick value", tick)
+		}
+	default:
+		t.Fatal("expected a tick")
+	}
+}
+
+func TestLogicalTicker_fire(t *testing.T) {
+	lt := NewLogicalTicker()
+
+	lt.Fire()
+
+	select {
+	case tick := <-lt.C():
+		if !tick.Equal(time.Time{}) {
+			t.Fatal("unexpected tick value", tick)
+		}
+	default:
+		t.Fatal("expected a tick")
+	}
+}
+
+func TestLogicalTicker_nonBlocking(t *testing.T) {
+	lt := NewLogicalTicker()
+
+	// fills the buffered channel, further advances must not block
+	lt.Advance(time.Second)
+	lt.Advance(time.Second)
+	lt.Fire()
+}
+
+func TestPipe_Start_withLogicalTicker(t *testing.T) {
+	var (
+		pipe  Pipe
+		lt    = NewLogicalTicker()
+		count int
+	)
+
+	pipe.AddInput(
+		func(ctx context.Context) (interface{}, bool, error) {
+			count++
+			return count, true, nil
+		},
+	)
+
+	values := make(chan interface{})
+
+	pipe.AddOutput(
+		func(ctx context.Context, value interface{}) error {
+			values <- value
+			return nil
+		},
+	)
+
+	pipe.Start(context.Background(), time.Hour, WithTickerFunc(func(time.Duration) Ticker { return lt }))
+
+	// the first tick happens immediately, regardless of the ticker
+	if v := <-values; v != 1 {
+		t.Fatal("unexpected value", v)
+	}
+
+	lt.Advance(time.Hour)
+	if v := <-values; v != 2 {
+		t.Fatal("unexpected value", v)
+	}
+
+	lt.Advance(time.Hour)
+	if v := <-values; v != 3 {
+		t.Fatal("unexpected value", v)
+	}
+
+	pipe.Stop()
+	<-pipe.Done()
+
+	if err := pipe.Err(); err != nil {
+		t.Fatal(err)
+	}
+}